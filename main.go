@@ -1,35 +1,37 @@
 package main
 
 import (
-	"encoding/binary"
 	"fmt"
 	"github.com/alecthomas/kong"
 	"github.com/gammazero/dirtree"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
-	"hash/maphash"
-	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
-	"sync"
+
+	"naivecmp/pkg/naivecmp"
 )
 
 var CLI struct {
-	DirA       string `arg:"" help:"Directory A." type:"existingdir"`
-	DirB       string `arg:"" help:"Directory B." type:"existingdir"`
-	UseModTime bool   `default:"true" help:"Use file mod time (default true)."`
-	UseSize    bool   `default:"true" help:"Use file size (default true)."`
-	UseMode    bool   `default:"false" help:"Use file mode (default false)."`
-	UseName    bool   `default:"false" help:"Use file name even when there is no collision (default false)."`
-	UsePath    bool   `default:"false" help:"Use file directory path (default false)."`
-	Workers    int    `default:"6" help:"Count of parallel workers per directory."`
-	Text       bool   `default:"false" help:"Print results in text instead of GUI."`
-	FileCount  bool   `default:"true" help:"Print file counts in GUI mode (default true)."`
-	Debug      bool   `default:"false" help:"Print debug output, useful to troubleshoot issues."`
+	DirA          string `arg:"" help:"Directory A, or a tar/tar.gz/zip archive."`
+	DirB          string `arg:"" help:"Directory B, or a tar/tar.gz/zip archive."`
+	UseModTime    bool   `default:"true" help:"Use file mod time (default true)."`
+	UseSize       bool   `default:"true" help:"Use file size (default true)."`
+	UseMode       bool   `default:"false" help:"Use file mode (default false)."`
+	UseName       bool   `default:"false" help:"Fold the file name into the hash (default false)."`
+	UsePath       bool   `default:"false" help:"Use file directory path (default false)."`
+	UseOwner      bool   `default:"false" help:"Use file owner uid/gid, when the source exposes one (default false)."`
+	UseLinkTarget bool   `default:"false" help:"Use symlink target, when the source exposes one (default false)."`
+	Workers       int    `default:"6" help:"Count of parallel workers per directory."`
+	Text          bool   `default:"false" help:"Print results in text instead of GUI."`
+	FileCount     bool   `default:"true" help:"Print file counts in GUI mode (default true)."`
+	Debug         bool   `default:"false" help:"Print debug output, useful to troubleshoot issues."`
+	Rules         string `help:"Path to a YAML rules file; evaluates pass/fail gates instead of showing the TUI or text output, and exits 1 if any rule fails."`
+	Cache         string `help:"Directory to store a persistent hash cache in, keyed by path/mtime/size, to speed up incremental re-runs."`
+	CacheMem      int    `default:"1000000" help:"Max hash cache rows held in memory per side (default 1000000)."`
+	Porcelain     bool   `default:"false" help:"Print a git-status-style report (one status char + path per line) instead of the TUI or text output, with renames detected across A/B."`
 }
 
 func main() {
@@ -46,174 +48,82 @@ func main() {
 	}
 }
 
-var seed = maphash.MakeSeed()
-
 const FileCountPlaceHolder = "[?] "
 
-func hash(filePath string, info fs.FileInfo) uint64 {
-	data := make([]byte, 0, 32)
-	if CLI.UseMode {
-		data = binary.LittleEndian.AppendUint32(data, uint32(info.Mode()))
-	}
-	if CLI.UseModTime {
-		data = binary.LittleEndian.AppendUint64(data, uint64(info.ModTime().UnixNano()))
-	}
-	if CLI.UseSize {
-		data = binary.LittleEndian.AppendUint64(data, uint64(info.Size()))
-	}
-	if CLI.UsePath {
-		fileDir := filepath.Dir(filePath) + string(filepath.Separator)
-		data = append(data, []byte(fileDir)...)
-	}
-	if CLI.UseName {
-		data = append(data, []byte(info.Name())...)
-	}
-	return maphash.Bytes(seed, data)
-}
-
-type DirMap struct {
-	root      *dirtree.Dirent
-	basePath  string
-	hashMap   map[uint64][]*dirtree.Dirent
-	entryMap  map[*dirtree.Dirent]uint64
-	mapMutex  sync.Mutex
-	treeMutex sync.Mutex
-	wg        sync.WaitGroup
-}
-
-type ScanEntry struct {
-	path  string
-	isDir bool
-}
-
-func mapDir(dir string) (*DirMap, error) {
-	dirMap := DirMap{
-		root:     dirtree.New(""),
-		basePath: dir,
-		hashMap:  map[uint64][]*dirtree.Dirent{},
-		entryMap: map[*dirtree.Dirent]uint64{},
-	}
-	dirChan := make(chan ScanEntry, 1024)
-	for i := 0; i < CLI.Workers; i++ {
-		go func() {
-			for entry := range dirChan {
-				if err := mapWorker(entry, &dirMap, dirChan); err != nil {
-					log.Fatalln(err)
-				}
-			}
-		}()
+func work() error {
+	opts := naivecmp.Options{
+		UseModTime:    CLI.UseModTime,
+		UseSize:       CLI.UseSize,
+		UseMode:       CLI.UseMode,
+		UseName:       CLI.UseName,
+		UsePath:       CLI.UsePath,
+		UseOwner:      CLI.UseOwner,
+		UseLinkTarget: CLI.UseLinkTarget,
+		Workers:       CLI.Workers,
+		CacheDir:      CLI.Cache,
+		CacheMem:      CLI.CacheMem,
+	}
+	log.Println("Mapping and comparing directories...")
+	result, err := naivecmp.Compare(CLI.DirA, CLI.DirB, opts)
+	if err != nil {
+		return err
 	}
-	dirMap.wg.Add(1)
-	dirChan <- ScanEntry{"", true}
-	dirMap.wg.Wait()
-	return &dirMap, nil
-}
-
-func mapWorker(scanEntry ScanEntry, dirMap *DirMap, scanChan chan ScanEntry) error {
-	defer dirMap.wg.Done()
-	if scanEntry.isDir {
-		children, err := os.ReadDir(filepath.Join(dirMap.basePath, scanEntry.path))
+	log.Println("Done")
+	if CLI.Rules != "" {
+		ruleSet, err := loadRuleSet(CLI.Rules)
 		if err != nil {
 			return err
 		}
-		dirMap.wg.Add(len(children))
-		for _, child := range children {
-			newEntry := ScanEntry{filepath.Join(scanEntry.path, child.Name()), child.IsDir()}
-			select {
-			case scanChan <- newEntry:
-			default:
-				if err := mapWorker(newEntry, dirMap, scanChan); err != nil {
-					return err
-				}
-			}
+		if printRuleReport(evaluateRules(ruleSet, result.Changes)) {
+			os.Exit(1)
 		}
 		return nil
 	}
-	curNode := dirMap.root
-	dirMap.treeMutex.Lock()
-	for _, part := range strings.Split(scanEntry.path, string(os.PathSeparator)) {
-		newNode := curNode.Child(part)
-		var err error
-		if newNode == nil {
-			newNode, err = curNode.Add(part)
-			if err != nil {
-				return err
-			}
-		}
-		curNode = newNode
-	}
-	dirMap.treeMutex.Unlock()
-	info, err := os.Lstat(filepath.Join(dirMap.basePath, scanEntry.path))
-	if err != nil {
-		return err
-	}
-	h := hash(scanEntry.path, info)
-	dirMap.mapMutex.Lock()
-	if v, ok := dirMap.hashMap[h]; ok {
-		dirMap.hashMap[h] = append(v, curNode)
-	} else {
-		dirMap.hashMap[h] = []*dirtree.Dirent{curNode}
-	}
-	dirMap.entryMap[curNode] = h
-	dirMap.mapMutex.Unlock()
-	return nil
-}
-
-func walkDir(mapA, mapB *DirMap, dirA *dirtree.Dirent, diff *dirtree.Dirent) error {
-	isDir := false
-	dirA.ForChild(func(d *dirtree.Dirent) bool {
-		isDir = true
-		if err := walkDir(mapA, mapB, d, diff); err != nil {
-			log.Fatalln(err)
+	if CLI.Porcelain {
+		for _, entry := range result.Porcelain() {
+			fmt.Println(entry.String())
 		}
-		return true
-	})
-	if isDir {
 		return nil
 	}
-	h, ok := mapA.entryMap[dirA]
-	if !ok {
-		// this is a directory
-		return nil
-	}
-	var matched bool
-	if matches, ok := mapB.hashMap[h]; !ok {
-		// file is missing from dirB
-		matched = false
-	} else if len(matches) == 1 {
-		// file is present in dirB
-		matched = true
-	} else {
-		// if multiple files in dirB have the same hash, fall back to comparing full path
-		matched = false
-		for _, match := range matches {
-			if match.Path() == dirA.Path() {
-				matched = true
-				break
+	if CLI.Text {
+		if CLI.Debug {
+			fmt.Println("========== Debug ==========")
+			if err := result.Walk(debugVisitor{}); err != nil {
+				return err
 			}
 		}
-	}
-	if !matched {
-		parts := strings.Split(dirA.Path(), "/")
-		curNode := diff
-		for _, part := range parts {
-			if part == "" {
-				continue
-			}
-			newNode := curNode.Child(part)
-			var err error
-			if newNode == nil {
-				newNode, err = curNode.Add(part)
-				if err != nil {
-					return err
-				}
-			}
-			curNode = newNode
+		fmt.Printf("========== Only in %s ==========\n", CLI.DirA)
+		printDir(result.DiffA)
+		fmt.Printf("========== Only in %s ==========\n", CLI.DirB)
+		printDir(result.DiffB)
+	} else {
+		if err := renderUI(result.DiffA, result.DiffB); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// debugVisitor prints every file path naivecmp.Compare looked at, labelled
+// by which side(s) it came from, for --debug troubleshooting. Directories
+// are not printed.
+type debugVisitor struct{}
+
+func (debugVisitor) OnOnlyInA(path string, info naivecmp.FileInfo) {
+	fmt.Printf("A %s %d\n", path, info.Hash)
+}
+
+func (debugVisitor) OnOnlyInB(path string, info naivecmp.FileInfo) {
+	fmt.Printf("B %s %d\n", path, info.Hash)
+}
+
+func (debugVisitor) OnMatched(pathA, pathB string, info naivecmp.FileInfo) {
+	fmt.Printf("= %s %d\n", pathA, info.Hash)
+}
+
+func (debugVisitor) OnDirEnter(path string) {}
+func (debugVisitor) OnDirLeave(path string) {}
+
 func hasChildren(d *dirtree.Dirent) bool {
 	result := false
 	d.ForChild(func(d *dirtree.Dirent) bool {
@@ -280,72 +190,6 @@ func printDir(dir *dirtree.Dirent) {
 	fmt.Println(dir.Path())
 }
 
-func work() error {
-	log.Println("Mapping directories...")
-	var wg sync.WaitGroup
-	wg.Add(2)
-	var dirA, dirB *DirMap
-	go func() {
-		result, err := mapDir(CLI.DirA)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		dirA = result
-		log.Println("Finished " + CLI.DirA)
-		wg.Done()
-	}()
-	go func() {
-		result, err := mapDir(CLI.DirB)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		dirB = result
-		log.Println("Finished " + CLI.DirB)
-		wg.Done()
-	}()
-	wg.Wait()
-	log.Println("Comparing...")
-	diffA := dirtree.New("")
-	if err := walkDir(dirA, dirB, dirA.root, diffA); err != nil {
-		return err
-	}
-	diffB := dirtree.New("")
-	if err := walkDir(dirB, dirA, dirB.root, diffB); err != nil {
-		return err
-	}
-	log.Println("Done")
-	if CLI.Text {
-		if CLI.Debug {
-			fmt.Printf("========== Debug for %s ==========\n", CLI.DirA)
-			printDebug(dirA)
-			fmt.Printf("========== Debug for %s ==========\n", CLI.DirB)
-			printDebug(dirB)
-		}
-		fmt.Printf("========== Only in %s ==========\n", CLI.DirA)
-		printDir(diffA)
-		fmt.Printf("========== Only in %s ==========\n", CLI.DirB)
-		printDir(diffB)
-	} else {
-		if err := renderUI(diffA, diffB); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func printDebug(dirMap *DirMap) {
-	entries := make([]*dirtree.Dirent, 0, len(dirMap.entryMap))
-	for entry := range dirMap.entryMap {
-		entries = append(entries, entry)
-	}
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Path() < entries[j].Path()
-	})
-	for _, entry := range entries {
-		fmt.Printf("%s %d\n", entry.Path(), dirMap.entryMap[entry])
-	}
-}
-
 type NodeReference struct {
 	entry     *dirtree.Dirent
 	isDir     bool
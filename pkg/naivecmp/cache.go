@@ -0,0 +1,212 @@
+package naivecmp
+
+import (
+	"container/list"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// cacheRow is one persisted row of the hash cache: everything needed to
+// decide whether a path's hash can be reused without recomputing it.
+type cacheRow struct {
+	RelPath   string
+	ModTimeNs int64
+	Size      int64
+	Mode      uint32
+	Hash      uint64
+}
+
+// cacheEntry is what the LRU actually holds: a row plus whether it's been
+// visited this run. Keeping that flag here instead of in a parallel map
+// means "visited" is bounded by the same capacity as the rest of the
+// cache, instead of growing for every path a run ever touches.
+type cacheEntry struct {
+	row     cacheRow
+	visited bool
+}
+
+// HashCache is a persistent, on-disk (path, mtime, size) -> hash table for
+// one side of a comparison, bounded in memory by a small LRU so a tree with
+// millions of entries doesn't force the whole cache resident at once. Rows
+// are written back asynchronously through pending so the hot ingest path
+// never blocks on disk I/O. A row evicted from the LRU is dropped: close()
+// persists only the rows the LRU still holds.
+type HashCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	index    map[string]*list.Element
+	file     string
+	pending  chan cacheRow
+	wg       sync.WaitGroup
+}
+
+// cacheFileName derives a stable, collision-resistant file name for
+// basePath's cache, scoped to opts' hash-affecting fields so that two runs
+// against the same --cache dir with a different Use* flag set land in
+// different files instead of silently reusing hashes computed under the
+// other flag set.
+func cacheFileName(basePath string, opts Options) string {
+	abs, err := filepath.Abs(basePath)
+	if err != nil {
+		abs = basePath
+	}
+	h := fnv.New64a()
+	h.Write([]byte(abs))
+	h.Write([]byte{0})
+	h.Write(optionsFingerprint(opts))
+	return fmt.Sprintf("%x.gob", h.Sum64())
+}
+
+// optionsFingerprint encodes the Options fields that affect how a hash is
+// computed, so cacheFileName can key the cache on them alongside basePath.
+func optionsFingerprint(opts Options) []byte {
+	flags := []bool{
+		opts.UseModTime, opts.UseSize, opts.UseMode,
+		opts.UseName, opts.UsePath, opts.UseOwner, opts.UseLinkTarget,
+	}
+	data := make([]byte, len(flags))
+	for i, flag := range flags {
+		if flag {
+			data[i] = 1
+		}
+	}
+	return data
+}
+
+// openHashCache loads cacheDir's on-disk cache for basePath, if any, and
+// starts its async write-back worker. It returns a nil *HashCache (not an
+// error) when cacheDir is empty, so callers can pass it straight through to
+// a Source/DirMap; lookup, record and close are safe no-ops on a nil receiver.
+func openHashCache(cacheDir, basePath string, memBudget int, opts Options) (*HashCache, error) {
+	if cacheDir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return nil, err
+	}
+	cache := &HashCache{
+		capacity: memBudget,
+		ll:       list.New(),
+		index:    map[string]*list.Element{},
+		file:     filepath.Join(cacheDir, cacheFileName(basePath, opts)),
+		pending:  make(chan cacheRow, 1024),
+	}
+	f, err := os.Open(cache.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			cache.wg.Add(1)
+			go cache.writer()
+			return cache, nil
+		}
+		return nil, err
+	}
+	var rows []cacheRow
+	err = gob.NewDecoder(f).Decode(&rows)
+	f.Close()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	for _, row := range rows {
+		cache.put(row, false)
+	}
+	cache.wg.Add(1)
+	go cache.writer()
+	return cache, nil
+}
+
+func (c *HashCache) writer() {
+	defer c.wg.Done()
+	for row := range c.pending {
+		c.put(row, true)
+	}
+}
+
+// put inserts or refreshes row as the most-recently-used entry, evicting
+// the least-recently-used one if that pushes the cache past its capacity.
+// visited marks the entry as having been touched this run; a row loaded
+// from disk at startup hasn't been, so close() can tell a merely-cached
+// path from one this run actually confirmed still exists.
+func (c *HashCache) put(row cacheRow, visited bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.index[row.RelPath]; ok {
+		el.Value = cacheEntry{row, visited}
+		c.ll.MoveToFront(el)
+		return
+	}
+	el := c.ll.PushFront(cacheEntry{row, visited})
+	c.index[row.RelPath] = el
+	if c.capacity > 0 && c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.index, oldest.Value.(cacheEntry).row.RelPath)
+		}
+	}
+}
+
+// lookup returns the cached hash for relPath if it's still fresh (same
+// mtime and size). A hit, stale or not, counts as a visit so close() knows
+// not to evict a path that's merely been superseded this run.
+func (c *HashCache) lookup(relPath string, modTimeNs, size int64) (uint64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	c.mu.Lock()
+	el, ok := c.index[relPath]
+	if !ok {
+		c.mu.Unlock()
+		return 0, false
+	}
+	c.ll.MoveToFront(el)
+	entry := el.Value.(cacheEntry)
+	entry.visited = true
+	el.Value = entry
+	c.mu.Unlock()
+	if entry.row.ModTimeNs != modTimeNs || entry.row.Size != size {
+		return 0, false
+	}
+	return entry.row.Hash, true
+}
+
+// record queues a freshly computed hash for write-back; the writer goroutine
+// marks it visited when it lands in the LRU via put.
+func (c *HashCache) record(relPath string, modTimeNs, size int64, mode uint32, hash uint64) {
+	if c == nil {
+		return
+	}
+	c.pending <- cacheRow{RelPath: relPath, ModTimeNs: modTimeNs, Size: size, Mode: mode, Hash: hash}
+}
+
+// close stops the write-back worker, drops any cached path that wasn't
+// visited this run, and persists what's left to disk. Because "visited"
+// lives on the LRU entry itself, this bookkeeping costs nothing beyond the
+// capacity the cache was already bounded to.
+func (c *HashCache) close() error {
+	if c == nil {
+		return nil
+	}
+	close(c.pending)
+	c.wg.Wait()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rows := make([]cacheRow, 0, c.ll.Len())
+	for el := c.ll.Front(); el != nil; el = el.Next() {
+		entry := el.Value.(cacheEntry)
+		if entry.visited {
+			rows = append(rows, entry.row)
+		}
+	}
+	f, err := os.Create(c.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(rows)
+}
@@ -0,0 +1,27 @@
+package naivecmp
+
+import "testing"
+
+// TestHashCacheVisitedBoundedByCapacity covers the --cache-mem promise:
+// visited-tracking must live inside the LRU itself, not in a separate
+// structure that grows with every path a run ever touches, or the memory
+// bound the flag advertises wouldn't hold.
+func TestHashCacheVisitedBoundedByCapacity(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := openHashCache(dir, "base", 2, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, path := range []string{"a", "b", "c"} {
+		cache.record(path, int64(i), int64(i), 0, uint64(i))
+	}
+	if err := cache.close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cache.ll.Len(); got > 2 {
+		t.Fatalf("cache grew to %d entries, want <= capacity 2", got)
+	}
+	if got := len(cache.index); got > 2 {
+		t.Fatalf("cache index grew to %d entries, want <= capacity 2", got)
+	}
+}
@@ -0,0 +1,74 @@
+package naivecmp
+
+import (
+	"sync"
+
+	"github.com/gammazero/dirtree"
+)
+
+// Result is the outcome of comparing two directory trees. DiffA/DiffB are
+// tries of only the paths unique to (or modified on) each side, for
+// rendering a diff view; Changes is the flat merkletrie-style change
+// stream; Walk traverses the full comparison, matched paths included.
+type Result struct {
+	DiffA   *dirtree.Dirent
+	DiffB   *dirtree.Dirent
+	Changes []Change
+
+	dirA *DirMap
+	dirB *DirMap
+}
+
+// Compare walks a and b (each a directory, or a tar/tar.gz/tgz/zip
+// archive), hashes their entries according to opts, and returns the
+// resulting diff.
+func Compare(a, b string, opts Options) (*Result, error) {
+	var cacheA, cacheB *HashCache
+	if opts.CacheDir != "" {
+		var err error
+		if cacheA, err = openHashCache(opts.CacheDir, a, opts.CacheMem, opts); err != nil {
+			return nil, err
+		}
+		if cacheB, err = openHashCache(opts.CacheDir, b, opts.CacheMem, opts); err != nil {
+			return nil, err
+		}
+	}
+	sourceA, err := newSource(a, cacheA, opts)
+	if err != nil {
+		return nil, err
+	}
+	sourceB, err := newSource(b, cacheB, opts)
+	if err != nil {
+		return nil, err
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var dirA, dirB *DirMap
+	var errA, errB error
+	go func() {
+		defer wg.Done()
+		dirA, errA = mapDir(sourceA, cacheA, opts)
+	}()
+	go func() {
+		defer wg.Done()
+		dirB, errB = mapDir(sourceB, cacheB, opts)
+	}()
+	wg.Wait()
+	if errA != nil {
+		return nil, errA
+	}
+	if errB != nil {
+		return nil, errB
+	}
+	if err := cacheA.close(); err != nil {
+		return nil, err
+	}
+	if err := cacheB.close(); err != nil {
+		return nil, err
+	}
+	diffA, diffB, changes, err := diffTrees(dirA, dirB)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{DiffA: diffA, DiffB: diffB, Changes: changes, dirA: dirA, dirB: dirB}, nil
+}
@@ -0,0 +1,191 @@
+package naivecmp
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+
+	"github.com/gammazero/dirtree"
+)
+
+// ChangeType classifies how a path differs between dirA and dirB.
+type ChangeType int
+
+const (
+	ChangeDelete ChangeType = iota // present in dirA, missing from dirB
+	ChangeAdd                      // present in dirB, missing from dirA
+	ChangeModify                   // present in both, but the hash differs
+)
+
+func (c ChangeType) String() string {
+	switch c {
+	case ChangeDelete:
+		return "Delete"
+	case ChangeAdd:
+		return "Add"
+	case ChangeModify:
+		return "Modify"
+	default:
+		return "Unknown"
+	}
+}
+
+// Change describes a single path-level difference produced by diffTrees.
+type Change struct {
+	Path    string
+	Type    ChangeType
+	OldHash uint64 // hash in dirA, 0 if the path does not exist there
+	NewHash uint64 // hash in dirB, 0 if the path does not exist there
+}
+
+// computeSubtreeHashes walks dirMap's trie bottom-up and assigns every
+// directory a rolling hash derived from its children's names and hashes
+// (sorted by name), so two directories hash equal iff their subtrees are
+// identical. Leaf hashes are taken straight from entryMap.
+func computeSubtreeHashes(dirMap *DirMap) map[*dirtree.Dirent]uint64 {
+	result := make(map[*dirtree.Dirent]uint64, len(dirMap.entryMap))
+	var visit func(node *dirtree.Dirent) uint64
+	visit = func(node *dirtree.Dirent) uint64 {
+		if h, ok := dirMap.entryMap[node]; ok {
+			result[node] = h
+			return h
+		}
+		names := node.List()
+		data := make([]byte, 0, 32*len(names))
+		for _, name := range names {
+			child := node.Child(name)
+			childHash := visit(child)
+			data = append(data, []byte(name)...)
+			data = binary.LittleEndian.AppendUint64(data, childHash)
+		}
+		h := hashBytes(data)
+		result[node] = h
+		return h
+	}
+	visit(dirMap.root)
+	return result
+}
+
+// unionNames merges two already-sorted name slices into a sorted, deduped one.
+func unionNames(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	result := make([]string, 0, len(a)+len(b))
+	for _, names := range [][]string{a, b} {
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// diffTrees recursively compares mapA and mapB's tries, short-circuiting
+// whole subtrees whenever their rolling hashes match, and returns the
+// unmatched entries as dirtree.Dirent trees (for the existing text/TUI
+// renderers) alongside a flat merkletrie-style change stream.
+func diffTrees(mapA, mapB *DirMap) (diffA, diffB *dirtree.Dirent, changes []Change, err error) {
+	diffA = dirtree.New("")
+	diffB = dirtree.New("")
+	err = diffNode(mapA, mapB, mapA.root, mapB.root, diffA, diffB, &changes)
+	return
+}
+
+func diffNode(mapA, mapB *DirMap, a, b *dirtree.Dirent, diffA, diffB *dirtree.Dirent, changes *[]Change) error {
+	if a == nil && b == nil {
+		return nil
+	}
+	if a == nil {
+		return addOnly(mapB, b, diffB, ChangeAdd, changes)
+	}
+	if b == nil {
+		return addOnly(mapA, a, diffA, ChangeDelete, changes)
+	}
+	_, aIsFile := mapA.entryMap[a]
+	_, bIsFile := mapB.entryMap[b]
+	if aIsFile != bIsFile {
+		// a directory was replaced by a file (or vice versa): neither side
+		// matches, so report the whole of each as its own change.
+		if err := addOnly(mapA, a, diffA, ChangeDelete, changes); err != nil {
+			return err
+		}
+		return addOnly(mapB, b, diffB, ChangeAdd, changes)
+	}
+	if aIsFile {
+		ha, hb := mapA.entryMap[a], mapB.entryMap[b]
+		if ha != hb {
+			*changes = append(*changes, Change{Path: changePath(a), Type: ChangeModify, OldHash: ha, NewHash: hb})
+			if _, err := insertPath(diffA, a.Path()); err != nil {
+				return err
+			}
+			if _, err := insertPath(diffB, b.Path()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// both are directories: skip the whole subtree if its rolling hash matches
+	if mapA.subtreeHash[a] == mapB.subtreeHash[b] {
+		return nil
+	}
+	for _, name := range unionNames(a.List(), b.List()) {
+		if err := diffNode(mapA, mapB, a.Child(name), b.Child(name), diffA, diffB, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addOnly records every file under node (node itself if it is a file) as a
+// change of the given type and mirrors its path into dest.
+func addOnly(dirMap *DirMap, node *dirtree.Dirent, dest *dirtree.Dirent, changeType ChangeType, changes *[]Change) error {
+	if h, ok := dirMap.entryMap[node]; ok {
+		change := Change{Path: changePath(node), Type: changeType}
+		if changeType == ChangeDelete {
+			change.OldHash = h
+		} else {
+			change.NewHash = h
+		}
+		*changes = append(*changes, change)
+		_, err := insertPath(dest, node.Path())
+		return err
+	}
+	var err error
+	node.ForChild(func(child *dirtree.Dirent) bool {
+		if err = addOnly(dirMap, child, dest, changeType, changes); err != nil {
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// changePath is a Change's Path: node.Path() with its leading "/" (dirtree
+// roots every path there) stripped, so it reads like a normal relative
+// path a user would write in a glob instead of an absolute one.
+func changePath(node *dirtree.Dirent) string {
+	return strings.TrimPrefix(node.Path(), "/")
+}
+
+// insertPath ensures path (a "/"-joined dirtree path) exists under root,
+// creating intermediate directories as needed, and returns its leaf node.
+func insertPath(root *dirtree.Dirent, path string) (*dirtree.Dirent, error) {
+	curNode := root
+	for _, part := range strings.Split(path, "/") {
+		if part == "" {
+			continue
+		}
+		newNode := curNode.Child(part)
+		if newNode == nil {
+			var err error
+			newNode, err = curNode.Add(part)
+			if err != nil {
+				return nil, err
+			}
+		}
+		curNode = newNode
+	}
+	return curNode, nil
+}
@@ -0,0 +1,87 @@
+package naivecmp
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gammazero/dirtree"
+)
+
+// DirMap is one side of a comparison: a trie of every path a Source
+// produced, plus the per-file hashes and metadata needed to diff it
+// against another DirMap and to answer Visitor callbacks afterwards.
+type DirMap struct {
+	root        *dirtree.Dirent
+	hashMap     map[uint64][]*dirtree.Dirent
+	entryMap    map[*dirtree.Dirent]uint64
+	info        map[*dirtree.Dirent]FileInfo
+	subtreeHash map[*dirtree.Dirent]uint64
+	cache       *HashCache
+	opts        Options
+	mapMutex    sync.Mutex
+	treeMutex   sync.Mutex
+}
+
+func mapDir(source Source, cache *HashCache, opts Options) (*DirMap, error) {
+	dirMap := DirMap{
+		root:     dirtree.New(""),
+		hashMap:  map[uint64][]*dirtree.Dirent{},
+		entryMap: map[*dirtree.Dirent]uint64{},
+		info:     map[*dirtree.Dirent]FileInfo{},
+		cache:    cache,
+		opts:     opts,
+	}
+	if err := source.Walk(dirMap.ingest); err != nil {
+		return nil, err
+	}
+	dirMap.subtreeHash = computeSubtreeHashes(&dirMap)
+	return &dirMap, nil
+}
+
+// ingest records a single entry produced by a Source into the trie,
+// hashing it and updating hashMap/entryMap/info if it's a file. It's
+// called concurrently by FilesystemSource and serially by the archive
+// sources, so all tree/map mutation is guarded by dirMap's mutexes.
+func (dirMap *DirMap) ingest(entry FileInfo) error {
+	dirMap.treeMutex.Lock()
+	node, err := insertPath(dirMap.root, entry.Path)
+	dirMap.treeMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if entry.IsDir {
+		return nil
+	}
+	modTimeNs := entry.ModTime.UnixNano()
+	h, ok := dirMap.cache.lookup(entry.Path, modTimeNs, entry.Size)
+	if !ok {
+		h = hash(entry, dirMap.opts)
+		dirMap.cache.record(entry.Path, modTimeNs, entry.Size, uint32(entry.Mode), h)
+	}
+	entry.Hash = h
+	dirMap.mapMutex.Lock()
+	dirMap.hashMap[h] = append(dirMap.hashMap[h], node)
+	dirMap.entryMap[node] = h
+	dirMap.info[node] = entry
+	dirMap.mapMutex.Unlock()
+	return nil
+}
+
+// infoAt looks up the FileInfo ingested for path, if any. Unlike info,
+// which is keyed by trie node (and so only matches nodes from this same
+// DirMap's own root), this also works against paths read off a separate
+// tree, such as a diffA/diffB produced by diffTrees.
+func (dirMap *DirMap) infoAt(path string) (FileInfo, bool) {
+	node := dirMap.root
+	for _, part := range strings.Split(path, "/") {
+		if part == "" {
+			continue
+		}
+		node = node.Child(part)
+		if node == nil {
+			return FileInfo{}, false
+		}
+	}
+	info, ok := dirMap.info[node]
+	return info, ok
+}
@@ -0,0 +1,263 @@
+package naivecmp
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileInfo describes one path yielded by a Source: either a directory
+// (IsDir true, no hashable attributes) or a file/symlink with the metadata
+// hash() consumes. It's also the type Visitor callbacks receive, so it
+// must stay exported.
+type FileInfo struct {
+	Path       string
+	IsDir      bool
+	Size       int64
+	Mode       fs.FileMode
+	ModTime    time.Time
+	Uid        int
+	Gid        int
+	LinkTarget string
+	// Hash is the value naivecmp hashed this entry to, using whatever
+	// Options the comparison ran with. It's zero for directories.
+	Hash uint64
+}
+
+// Source produces the entries of a directory tree, whether it lives on disk
+// or inside an archive. Implementations decide for themselves whether
+// entries are produced concurrently (filesystem) or strictly in the order
+// the underlying stream delivers them (tar).
+type Source interface {
+	Walk(fn func(FileInfo) error) error
+}
+
+// normalizeArchivePath cleans a tar or zip entry name into the relative,
+// slash-separated form the rest of naivecmp expects: path.Clean collapses
+// away a "./" prefix (the layout tar -C dir . produces for every entry) and
+// any "//" runs, and the final check maps the "." Clean leaves behind for
+// the archive root itself to "", which the caller skips.
+func normalizeArchivePath(name string) string {
+	p := path.Clean(strings.Trim(name, "/"))
+	p = strings.TrimPrefix(p, "./")
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+// newSource inspects path and returns the Source that can read it: a plain
+// directory, or a tar, tar.gz/tgz, or zip archive. cache is consulted only
+// by FilesystemSource; archive sources ignore it.
+func newSource(path string, cache *HashCache, opts Options) (Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return FilesystemSource{basePath: path, cache: cache, opts: opts}, nil
+	}
+	switch {
+	case strings.HasSuffix(path, ".zip"):
+		return ZipSource{path: path}, nil
+	case strings.HasSuffix(path, ".tar"), strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		return TarSource{path: path, opts: opts}, nil
+	default:
+		return nil, fmt.Errorf("unsupported source %q: expected a directory, .tar, .tar.gz, .tgz or .zip file", path)
+	}
+}
+
+// FilesystemSource walks a directory on disk, fanning out os.ReadDir calls
+// across a worker pool the same way the original flat scan did.
+type FilesystemSource struct {
+	basePath string
+	cache    *HashCache
+	opts     Options
+}
+
+type fsScanEntry struct {
+	path  string
+	isDir bool
+}
+
+func (s FilesystemSource) Walk(fn func(FileInfo) error) error {
+	scanChan := make(chan fsScanEntry, 1024)
+	var wg sync.WaitGroup
+	var errMutex sync.Mutex
+	var firstErr error
+	recordErr := func(err error) {
+		errMutex.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		errMutex.Unlock()
+	}
+	var worker func(entry fsScanEntry)
+	worker = func(entry fsScanEntry) {
+		defer wg.Done()
+		if entry.isDir {
+			children, err := os.ReadDir(filepath.Join(s.basePath, entry.path))
+			if err != nil {
+				recordErr(err)
+				return
+			}
+			wg.Add(len(children))
+			for _, child := range children {
+				newEntry := fsScanEntry{filepath.Join(entry.path, child.Name()), child.IsDir()}
+				select {
+				case scanChan <- newEntry:
+				default:
+					worker(newEntry)
+				}
+			}
+			if entry.path != "" {
+				if err := fn(FileInfo{Path: filepath.ToSlash(entry.path), IsDir: true}); err != nil {
+					recordErr(err)
+				}
+			}
+			return
+		}
+		fullPath := filepath.Join(s.basePath, entry.path)
+		info, err := os.Lstat(fullPath)
+		if err != nil {
+			recordErr(err)
+			return
+		}
+		fileInfo := FileInfo{
+			Path:    filepath.ToSlash(entry.path),
+			Size:    info.Size(),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
+		}
+		if _, hit := s.cache.lookup(fileInfo.Path, info.ModTime().UnixNano(), info.Size()); hit {
+			// cached hash still matches mtime+size: skip the extra syscalls
+			// (owner lookup, readlink) that only feed a hash we won't recompute.
+			if err := fn(fileInfo); err != nil {
+				recordErr(err)
+			}
+			return
+		}
+		if s.opts.UseOwner {
+			if uid, gid, ok := ownerOf(info); ok {
+				fileInfo.Uid, fileInfo.Gid = int(uid), int(gid)
+			}
+		}
+		if s.opts.UseLinkTarget && info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(fullPath); err == nil {
+				fileInfo.LinkTarget = target
+			}
+		}
+		if err := fn(fileInfo); err != nil {
+			recordErr(err)
+		}
+	}
+	for i := 0; i < s.opts.workers(); i++ {
+		go func() {
+			for entry := range scanChan {
+				worker(entry)
+			}
+		}()
+	}
+	wg.Add(1)
+	scanChan <- fsScanEntry{"", true}
+	wg.Wait()
+	close(scanChan)
+	return firstErr
+}
+
+// TarSource reads a tar (optionally gzip-compressed) archive as a single
+// serial stream: tar.Reader has no random access, so entries are passed to
+// fn one at a time, in stream order, instead of fanned out like
+// FilesystemSource.
+type TarSource struct {
+	path string
+	opts Options
+}
+
+func (s TarSource) Walk(fn func(FileInfo) error) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(s.path, ".gz") || strings.HasSuffix(s.path, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entryPath := normalizeArchivePath(header.Name)
+		if entryPath == "" {
+			continue
+		}
+		fileInfo := FileInfo{
+			Path:    entryPath,
+			IsDir:   header.Typeflag == tar.TypeDir,
+			Size:    header.Size,
+			Mode:    header.FileInfo().Mode(),
+			ModTime: header.ModTime,
+		}
+		if s.opts.UseOwner {
+			fileInfo.Uid, fileInfo.Gid = header.Uid, header.Gid
+		}
+		if s.opts.UseLinkTarget {
+			fileInfo.LinkTarget = header.Linkname
+		}
+		if err := fn(fileInfo); err != nil {
+			return err
+		}
+	}
+}
+
+// ZipSource reads a zip archive's central directory. Unlike tar, the
+// central directory gives us every entry up front, but we still feed them
+// in serially since zip.File grants no concurrent-safe random access either.
+type ZipSource struct {
+	path string
+}
+
+func (s ZipSource) Walk(fn func(FileInfo) error) error {
+	zr, err := zip.OpenReader(s.path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, file := range zr.File {
+		entryPath := normalizeArchivePath(file.Name)
+		if entryPath == "" {
+			continue
+		}
+		fileInfo := FileInfo{
+			Path:    entryPath,
+			IsDir:   file.FileInfo().IsDir(),
+			Size:    int64(file.UncompressedSize64),
+			Mode:    file.Mode(),
+			ModTime: file.Modified,
+		}
+		if err := fn(fileInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
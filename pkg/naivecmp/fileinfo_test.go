@@ -0,0 +1,50 @@
+package naivecmp
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTarSourceStripsDotSlashPrefix covers the tar -C dir . layout, where
+// every entry name is rooted at "./" (and the archive root itself shows up
+// as a bare "."), to make sure those don't end up nested under a bogus "."
+// path instead of matching the same tree compared on disk.
+func TestTarSourceStripsDotSlashPrefix(t *testing.T) {
+	tarPath := filepath.Join(t.TempDir(), "archive.tar")
+	f, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	for _, name := range []string{".", "./sub/", "./sub/only_b.txt"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeDir}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	src := TarSource{path: tarPath}
+	var paths []string
+	if err := src.Walk(func(info FileInfo) error {
+		paths = append(paths, info.Path)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"sub", "sub/only_b.txt"}
+	if len(paths) != len(want) {
+		t.Fatalf("got paths %v, want %v", paths, want)
+	}
+	for i, p := range want {
+		if paths[i] != p {
+			t.Fatalf("got paths %v, want %v", paths, want)
+		}
+	}
+}
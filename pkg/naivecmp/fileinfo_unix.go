@@ -0,0 +1,18 @@
+//go:build !windows
+
+package naivecmp
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// ownerOf extracts the owning uid/gid from a platform fs.FileInfo, when the
+// underlying Sys() value exposes one.
+func ownerOf(info fs.FileInfo) (uid, gid uint32, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return stat.Uid, stat.Gid, true
+}
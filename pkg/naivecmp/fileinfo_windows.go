@@ -0,0 +1,10 @@
+//go:build windows
+
+package naivecmp
+
+import "io/fs"
+
+// ownerOf has no portable uid/gid on Windows, so --use-owner is a no-op there.
+func ownerOf(info fs.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
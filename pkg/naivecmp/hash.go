@@ -0,0 +1,44 @@
+package naivecmp
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"path/filepath"
+)
+
+// hashBytes must be deterministic across runs and processes (not just
+// within one), since the hash cache persists hashes to disk and reuses
+// them on later invocations.
+func hashBytes(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+func hash(entry FileInfo, opts Options) uint64 {
+	data := make([]byte, 0, 32)
+	if opts.UseMode {
+		data = binary.LittleEndian.AppendUint32(data, uint32(entry.Mode))
+	}
+	if opts.UseModTime {
+		data = binary.LittleEndian.AppendUint64(data, uint64(entry.ModTime.UnixNano()))
+	}
+	if opts.UseSize {
+		data = binary.LittleEndian.AppendUint64(data, uint64(entry.Size))
+	}
+	if opts.UsePath {
+		fileDir := filepath.Dir(entry.Path) + "/"
+		data = append(data, []byte(fileDir)...)
+	}
+	if opts.UseName {
+		data = append(data, []byte(filepath.Base(entry.Path))...)
+	}
+	if opts.UseOwner {
+		data = binary.LittleEndian.AppendUint32(data, uint32(entry.Uid))
+		data = binary.LittleEndian.AppendUint32(data, uint32(entry.Gid))
+	}
+	if opts.UseLinkTarget {
+		data = append(data, []byte(entry.LinkTarget)...)
+	}
+	return hashBytes(data)
+}
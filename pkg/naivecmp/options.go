@@ -0,0 +1,27 @@
+package naivecmp
+
+// Options controls how Compare hashes entries and scans both sides.
+type Options struct {
+	UseModTime    bool
+	UseSize       bool
+	UseMode       bool
+	UseName       bool
+	UsePath       bool
+	UseOwner      bool
+	UseLinkTarget bool
+	// Workers is the count of parallel filesystem scan workers per side.
+	// A value <= 0 falls back to a sane default.
+	Workers int
+	// CacheDir, if non-empty, stores a persistent hash cache there, keyed
+	// by path/mtime/size, to speed up incremental re-runs.
+	CacheDir string
+	// CacheMem bounds how many cache rows are held in memory per side.
+	CacheMem int
+}
+
+func (o Options) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return 6
+}
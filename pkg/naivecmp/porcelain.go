@@ -0,0 +1,166 @@
+package naivecmp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PorcelainStatus is a single git-status-style classification character.
+type PorcelainStatus byte
+
+const (
+	PorcelainAdded    PorcelainStatus = 'A'
+	PorcelainDeleted  PorcelainStatus = 'D'
+	PorcelainModified PorcelainStatus = 'M'
+	PorcelainRenamed  PorcelainStatus = 'R'
+)
+
+// PorcelainEntry is one line of a `git status --porcelain`-style report.
+// OldPath is set on every entry that pairs a delete with an add: each
+// PorcelainRenamed entry, and a PorcelainModified entry that came from
+// size/mtime pairing. String prints it only for PorcelainRenamed.
+type PorcelainEntry struct {
+	Status  PorcelainStatus
+	Path    string
+	OldPath string
+}
+
+func (e PorcelainEntry) String() string {
+	if e.Status == PorcelainRenamed {
+		return fmt.Sprintf("%c %s -> %s", e.Status, e.OldPath, e.Path)
+	}
+	return fmt.Sprintf("%c %s", e.Status, e.Path)
+}
+
+// Porcelain reclassifies Result.Changes the way `git status --porcelain`
+// would: an add and a delete that share a hash are folded into a single
+// rename, and an add and a delete that share a size and mtime but not a
+// hash — which happens when Options.UsePath or UseName folds the name
+// into the hash — are folded into a "modified" entry instead. Remaining
+// adds/deletes/modifies are reported as-is. Many-to-many hash or
+// size/mtime collisions are resolved by sorting both sides by path and
+// pairing them off positionally.
+func (r *Result) Porcelain() []PorcelainEntry {
+	var deletes, adds []Change
+	var entries []PorcelainEntry
+	for _, c := range r.Changes {
+		switch c.Type {
+		case ChangeDelete:
+			deletes = append(deletes, c)
+		case ChangeAdd:
+			adds = append(adds, c)
+		case ChangeModify:
+			entries = append(entries, PorcelainEntry{Status: PorcelainModified, Path: c.Path})
+		}
+	}
+
+	usedDel := make([]bool, len(deletes))
+	usedAdd := make([]bool, len(adds))
+
+	entries = append(entries, pairChanges(deletes, adds, usedDel, usedAdd, PorcelainRenamed,
+		func(c Change) (uint64, bool) { return c.OldHash, true },
+		func(c Change) (uint64, bool) { return c.NewHash, true })...)
+
+	entries = append(entries, pairChanges(deletes, adds, usedDel, usedAdd, PorcelainModified,
+		func(c Change) (sizeModTimeKey, bool) {
+			info, ok := r.dirA.infoAt(c.Path)
+			return sizeModTimeKey{info.Size, info.ModTime.UnixNano()}, ok
+		},
+		func(c Change) (sizeModTimeKey, bool) {
+			info, ok := r.dirB.infoAt(c.Path)
+			return sizeModTimeKey{info.Size, info.ModTime.UnixNano()}, ok
+		})...)
+
+	for i, c := range deletes {
+		if !usedDel[i] {
+			entries = append(entries, PorcelainEntry{Status: PorcelainDeleted, Path: c.Path})
+		}
+	}
+	for j, c := range adds {
+		if !usedAdd[j] {
+			entries = append(entries, PorcelainEntry{Status: PorcelainAdded, Path: c.Path})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+type sizeModTimeKey struct {
+	size    int64
+	modTime int64
+}
+
+// pairChanges groups not-yet-used deletes and adds by key and, for every
+// key present on both sides, pairs them off (see pairByPathOrder),
+// marking each pair used and emitting one entry of status per pair. K is
+// usually uint64 (a hash) or sizeModTimeKey.
+func pairChanges[K comparable](deletes, adds []Change, usedDel, usedAdd []bool, status PorcelainStatus,
+	delKey func(Change) (K, bool), addKey func(Change) (K, bool)) []PorcelainEntry {
+	delByKey := map[K][]int{}
+	for i, d := range deletes {
+		if usedDel[i] {
+			continue
+		}
+		if k, ok := delKey(d); ok {
+			delByKey[k] = append(delByKey[k], i)
+		}
+	}
+	var entries []PorcelainEntry
+	addByKey := map[K][]int{}
+	for j, a := range adds {
+		if usedAdd[j] {
+			continue
+		}
+		if k, ok := addKey(a); ok {
+			addByKey[k] = append(addByKey[k], j)
+		}
+	}
+	for key, delIdxs := range delByKey {
+		addIdxs, ok := addByKey[key]
+		if !ok {
+			continue
+		}
+		for _, pair := range pairByPathOrder(deletes, adds, delIdxs, addIdxs) {
+			usedDel[pair.delIdx] = true
+			usedAdd[pair.addIdx] = true
+			entries = append(entries, PorcelainEntry{
+				Status:  status,
+				Path:    adds[pair.addIdx].Path,
+				OldPath: deletes[pair.delIdx].Path,
+			})
+		}
+	}
+	return entries
+}
+
+type changePair struct {
+	delIdx int
+	addIdx int
+}
+
+// pairByPathOrder resolves a many-to-many collision (several deletes and
+// several adds sharing one key) by sorting both sides by path and pairing
+// them off positionally. That's not as precise as scoring every delete
+// against every add by common prefix length, but a bucket this large is
+// almost always a pile of same-size zero-byte or boilerplate files rather
+// than a handful of genuine renames, and sorting keeps the cost O(n log n)
+// instead of the O(n*m) a full cross-product would cost on a bucket with
+// thousands of entries on each side (e.g. every empty file in a tree
+// colliding on the same size/mtime key).
+func pairByPathOrder(deletes, adds []Change, delIdxs, addIdxs []int) []changePair {
+	sortedDel := append([]int(nil), delIdxs...)
+	sort.Slice(sortedDel, func(i, j int) bool { return deletes[sortedDel[i]].Path < deletes[sortedDel[j]].Path })
+	sortedAdd := append([]int(nil), addIdxs...)
+	sort.Slice(sortedAdd, func(i, j int) bool { return adds[sortedAdd[i]].Path < adds[sortedAdd[j]].Path })
+
+	n := len(sortedDel)
+	if len(sortedAdd) < n {
+		n = len(sortedAdd)
+	}
+	result := make([]changePair, n)
+	for i := 0; i < n; i++ {
+		result[i] = changePair{sortedDel[i], sortedAdd[i]}
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package naivecmp
+
+import (
+	"strings"
+	"testing"
+)
+
+type sliceSource []FileInfo
+
+func (s sliceSource) Walk(fn func(FileInfo) error) error {
+	for _, entry := range s {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestPorcelainPathsHaveNoLeadingSlash(t *testing.T) {
+	opts := Options{UseSize: true}
+	dirA, err := mapDir(sliceSource{{Path: "onlyA.txt", Size: 1}, {Path: "mod.txt", Size: 1}}, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirB, err := mapDir(sliceSource{{Path: "onlyB.txt", Size: 2}, {Path: "mod.txt", Size: 2}}, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, changes, err := diffTrees(dirA, dirB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &Result{Changes: changes, dirA: dirA, dirB: dirB}
+	entries := result.Porcelain()
+	if len(entries) == 0 {
+		t.Fatal("expected at least one porcelain entry")
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Path, "/") || strings.HasPrefix(entry.OldPath, "/") {
+			t.Fatalf("porcelain entry carries a leading slash: %q", entry.String())
+		}
+	}
+}
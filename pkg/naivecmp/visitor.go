@@ -0,0 +1,82 @@
+package naivecmp
+
+import "github.com/gammazero/dirtree"
+
+// Visitor receives a depth-first walk of the full comparison tree, not
+// just the diff. A path that is a file on both sides is reported via
+// OnMatched, even when its hash differs; consult Result.Changes if you
+// need to tell matched-and-identical from matched-but-modified apart. A
+// path that is a file on one side and a directory on the other is
+// reported via OnOnlyInA and OnOnlyInB instead.
+type Visitor interface {
+	OnOnlyInA(path string, info FileInfo)
+	OnOnlyInB(path string, info FileInfo)
+	OnMatched(pathA, pathB string, info FileInfo)
+	OnDirEnter(path string)
+	OnDirLeave(path string)
+}
+
+// Walk visits every path present in either side of the comparison,
+// depth-first, calling the Visitor method that matches it.
+func (r *Result) Walk(v Visitor) error {
+	return walkVisit(r.dirA, r.dirB, r.dirA.root, r.dirB.root, "", v)
+}
+
+func walkVisit(mapA, mapB *DirMap, a, b *dirtree.Dirent, path string, v Visitor) error {
+	if a == nil && b == nil {
+		return nil
+	}
+	if a == nil {
+		return visitOnly(mapB, b, path, v.OnOnlyInB, v)
+	}
+	if b == nil {
+		return visitOnly(mapA, a, path, v.OnOnlyInA, v)
+	}
+	_, aIsFile := mapA.entryMap[a]
+	_, bIsFile := mapB.entryMap[b]
+	if aIsFile != bIsFile {
+		if err := visitOnly(mapA, a, path, v.OnOnlyInA, v); err != nil {
+			return err
+		}
+		return visitOnly(mapB, b, path, v.OnOnlyInB, v)
+	}
+	if aIsFile {
+		v.OnMatched(path, path, mapB.info[b])
+		return nil
+	}
+	v.OnDirEnter(path)
+	for _, name := range unionNames(a.List(), b.List()) {
+		if err := walkVisit(mapA, mapB, a.Child(name), b.Child(name), joinPath(path, name), v); err != nil {
+			return err
+		}
+	}
+	v.OnDirLeave(path)
+	return nil
+}
+
+// visitOnly reports node (and, if it's a directory, everything beneath it)
+// as belonging to one side only, via emit/OnDirEnter/OnDirLeave.
+func visitOnly(dirMap *DirMap, node *dirtree.Dirent, path string, emit func(string, FileInfo), v Visitor) error {
+	if info, ok := dirMap.info[node]; ok {
+		emit(path, info)
+		return nil
+	}
+	v.OnDirEnter(path)
+	var err error
+	node.ForChild(func(child *dirtree.Dirent) bool {
+		if e := visitOnly(dirMap, child, joinPath(path, child.String()), emit, v); e != nil {
+			err = e
+			return false
+		}
+		return true
+	})
+	v.OnDirLeave(path)
+	return err
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
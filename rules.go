@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"naivecmp/pkg/naivecmp"
+)
+
+// RuleSet is the schema for --rules YAML files. It lets naivecmp gate CI
+// builds instead of just reporting a diff interactively.
+type RuleSet struct {
+	MaxOnlyInA     *int     `yaml:"max-only-in-a"`
+	MaxOnlyInB     *int     `yaml:"max-only-in-b"`
+	ForbiddenPaths []string `yaml:"forbidden-paths"`
+	RequiredEqual  []string `yaml:"required-equal"`
+	IgnorePaths    []string `yaml:"ignore-paths"`
+}
+
+func loadRuleSet(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rs RuleSet
+	if err := yaml.Unmarshal(data, &rs); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// RuleStatus is the outcome of evaluating a single rule.
+type RuleStatus string
+
+const (
+	RulePass RuleStatus = "PASS"
+	RuleFail RuleStatus = "FAIL"
+	RuleSkip RuleStatus = "SKIP"
+)
+
+// RuleResult reports one rule's verdict plus the paths that caused it.
+type RuleResult struct {
+	Name      string
+	Status    RuleStatus
+	Offenders []string
+}
+
+// matchGlob matches p against pattern, supporting a trailing "/**" for
+// any-depth recursive matches in addition to plain path.Match globs.
+func matchGlob(pattern, p string) bool {
+	if idx := strings.Index(pattern, "/**"); idx >= 0 {
+		prefix := pattern[:idx]
+		return p == prefix || strings.HasPrefix(p, prefix+"/")
+	}
+	ok, _ := path.Match(pattern, p)
+	return ok
+}
+
+func matchesAnyGlob(globs []string, p string) bool {
+	for _, g := range globs {
+		if matchGlob(g, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func countResult(name string, count, max int, offenders []string) RuleResult {
+	status := RulePass
+	if count > max {
+		status = RuleFail
+	}
+	return RuleResult{Name: name, Status: status, Offenders: offenders}
+}
+
+func globResult(name string, offenders []string) RuleResult {
+	status := RulePass
+	if len(offenders) > 0 {
+		status = RuleFail
+	}
+	return RuleResult{Name: name, Status: status, Offenders: offenders}
+}
+
+// evaluateRules prunes changes matching IgnorePaths, then runs every rule
+// present in rs against what's left.
+func evaluateRules(rs *RuleSet, changes []naivecmp.Change) []RuleResult {
+	filtered := make([]naivecmp.Change, 0, len(changes))
+	for _, c := range changes {
+		if matchesAnyGlob(rs.IgnorePaths, c.Path) {
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+	var results []RuleResult
+	if rs.MaxOnlyInA != nil {
+		var offenders []string
+		for _, c := range filtered {
+			if c.Type == naivecmp.ChangeDelete {
+				offenders = append(offenders, c.Path)
+			}
+		}
+		results = append(results, countResult("max-only-in-a", len(offenders), *rs.MaxOnlyInA, offenders))
+	} else {
+		results = append(results, RuleResult{Name: "max-only-in-a", Status: RuleSkip})
+	}
+	if rs.MaxOnlyInB != nil {
+		var offenders []string
+		for _, c := range filtered {
+			if c.Type == naivecmp.ChangeAdd {
+				offenders = append(offenders, c.Path)
+			}
+		}
+		results = append(results, countResult("max-only-in-b", len(offenders), *rs.MaxOnlyInB, offenders))
+	} else {
+		results = append(results, RuleResult{Name: "max-only-in-b", Status: RuleSkip})
+	}
+	if len(rs.ForbiddenPaths) > 0 {
+		var offenders []string
+		for _, c := range filtered {
+			if matchesAnyGlob(rs.ForbiddenPaths, c.Path) {
+				offenders = append(offenders, c.Path)
+			}
+		}
+		results = append(results, globResult("forbidden-paths", offenders))
+	} else {
+		results = append(results, RuleResult{Name: "forbidden-paths", Status: RuleSkip})
+	}
+	if len(rs.RequiredEqual) > 0 {
+		var offenders []string
+		for _, c := range filtered {
+			if matchesAnyGlob(rs.RequiredEqual, c.Path) {
+				offenders = append(offenders, c.Path)
+			}
+		}
+		results = append(results, globResult("required-equal", offenders))
+	} else {
+		results = append(results, RuleResult{Name: "required-equal", Status: RuleSkip})
+	}
+	return results
+}
+
+// printRuleReport writes a rule-by-rule PASS/FAIL/SKIP report to stdout and
+// reports whether any rule failed.
+func printRuleReport(results []RuleResult) (failed bool) {
+	for _, result := range results {
+		fmt.Printf("[%s] %s\n", result.Status, result.Name)
+		for _, offender := range result.Offenders {
+			fmt.Printf("    %s\n", offender)
+		}
+		if result.Status == RuleFail {
+			failed = true
+		}
+	}
+	return failed
+}
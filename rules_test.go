@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"naivecmp/pkg/naivecmp"
+)
+
+func TestEvaluateRulesForbiddenPaths(t *testing.T) {
+	changes := []naivecmp.Change{
+		{Path: "foo/bar.txt", Type: naivecmp.ChangeAdd},
+	}
+	rs := &RuleSet{ForbiddenPaths: []string{"foo/**"}}
+	results := evaluateRules(rs, changes)
+	var forbidden *RuleResult
+	for i := range results {
+		if results[i].Name == "forbidden-paths" {
+			forbidden = &results[i]
+		}
+	}
+	if forbidden == nil || forbidden.Status != RuleFail {
+		t.Fatalf("expected forbidden-paths to fail on a bare (non-/-prefixed) glob, got %+v", results)
+	}
+	if len(forbidden.Offenders) != 1 || forbidden.Offenders[0] != "foo/bar.txt" {
+		t.Fatalf("expected foo/bar.txt as the offender, got %+v", forbidden.Offenders)
+	}
+}
+
+func TestEvaluateRulesSkipsAbsentRules(t *testing.T) {
+	results := evaluateRules(&RuleSet{}, nil)
+	for _, result := range results {
+		if result.Status != RuleSkip {
+			t.Fatalf("expected every rule to be SKIP when the rules file sets none of them, got %+v", result)
+		}
+	}
+}